@@ -19,14 +19,17 @@
 package circuitbreaker
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	errFailed error = errors.New("Failed!! got error")
-	errOpen   error = errors.New("Circuit Open")
+	errFailed          error = errors.New("Failed!! got error")
+	errOpen            error = errors.New("Circuit Open")
+	errTooManyRequests error = errors.New("Too many requests in half-open state")
 )
 
 // State defines the state of the circuit
@@ -47,17 +50,32 @@ func (s State) String() string {
 }
 
 /*
- 	CircuitBreaker acts as proxy for requests to a particular service.
-	It opens the circuit if requests are likely to get fail otherwise
-	allows the requests to pass the circuit.
+	Tracking holds the circuit's state machine: its current state, counters
+	and trip/untrip decisions, decoupled from any particular call-site shape.
+	Callers that don't fit Spark's func() (T, error) closure model (streaming
+	clients, connection pools, pipelined protocols) can drive a Tracking
+	directly via BeforeRequest/AfterRequest instead.
 */
-type CircuitBreaker struct {
+type Tracking struct {
 	circuitName string
 
 	currentState State
 	currentTime  time.Time
 	counters     *CircuitCounters
 
+	// stateEnteredAt is when currentState was entered. Unlike
+	// currentTime (which the closed-state Interval reset also
+	// rewrites), only transitionTo touches this, so it gives an
+	// accurate "time spent in state" for transition.duration.
+	stateEnteredAt time.Time
+
+	// generation is bumped on every state transition. AfterRequest
+	// compares the generation it was handed by BeforeRequest against
+	// the current one, so a result that arrives after the circuit has
+	// already moved on to a new state is dropped instead of corrupting
+	// the new state's counters.
+	generation uint64
+
 	// func to transit circuit state from close to open state
 	tripCircuit func(CircuitCounters) bool
 
@@ -68,67 +86,219 @@ type CircuitBreaker struct {
 	// to half-open state
 	openTime time.Duration
 
+	// time duration after which counters are cleared while the
+	// circuit is in closed state. Interval <= 0 disables this and
+	// counters are only reset on a state change, as before.
+	interval time.Duration
+
+	// maximum number of requests allowed to pass through while the
+	// circuit is half-open. Defaults to 1.
+	maxRequests uint32
+
+	// number of requests currently in-flight while half-open
+	halfOpenRequests uint32
+
+	// onStateChange, if set, fires whenever currentState changes.
+	onStateChange func(name string, from, to State)
+
+	// onTrip and onReset, if set, fire on transitions into the open
+	// and closed states respectively, mirroring vulcand/oxy cbreaker's
+	// split callbacks.
+	onTrip  func()
+	onReset func()
+
+	// isSuccessful classifies a request's error as a circuit failure
+	// or not, letting callers treat errors like context.Canceled or
+	// HTTP 4xx as non-failures. Defaults to err == nil.
+	isSuccessful func(error) bool
+
+	// metrics receives every recorded outcome and state transition, for
+	// callers that want observability beyond the OnStateChange/OnTrip/
+	// OnReset callbacks. Defaults to a no-op sink.
+	metrics MetricsSink
+
 	lock *sync.Mutex
 }
 
+// transition describes a single state change, used to fire the
+// OnStateChange/OnTrip/OnReset callbacks once the lock is released. duration
+// is how long the circuit spent in the "from" state before transitioning.
+type transition struct {
+	from, to State
+	duration time.Duration
+}
+
+/*
+	MetricsSink receives circuit breaker events for external
+	observability (dashboards, alerting, SLO tracking). Implementations
+	must be safe for concurrent use; all methods are invoked outside of
+	the circuit's internal lock, mirroring OnStateChange/OnTrip/OnReset.
+*/
+type MetricsSink interface {
+	RecordSuccess(circuitName string)
+	RecordFailure(circuitName string)
+	RecordRejection(circuitName string)
+	RecordTimeout(circuitName string)
+	RecordStateChange(circuitName string, from, to State, duration time.Duration)
+}
+
+// noopMetricsSink is the default MetricsSink; it discards every event.
+type noopMetricsSink struct{}
+
+func (noopMetricsSink) RecordSuccess(circuitName string)   {}
+func (noopMetricsSink) RecordFailure(circuitName string)   {}
+func (noopMetricsSink) RecordRejection(circuitName string) {}
+func (noopMetricsSink) RecordTimeout(circuitName string)   {}
+func (noopMetricsSink) RecordStateChange(circuitName string, from, to State, duration time.Duration) {
+}
+
+/*
+ 	CircuitBreaker acts as proxy for requests to a particular service.
+	It opens the circuit if requests are likely to get fail otherwise
+	allows the requests to pass the circuit.
+*/
+type CircuitBreaker[T any] struct {
+	*Tracking
+}
+
 /*
 	CircuitCounters are counters for the circuit
 	which is used to determine/change the state of
 	circuit.
 */
-// TODO implement Timeout and Rejection counter feedback to circuit
 type CircuitCounters struct {
 	Failure   int64
 	Success   int64
 	Timeout   int64
 	Rejection int64
+
+	// ConsecutiveSuccesses and ConsecutiveFailures track the current
+	// streak of successes/failures, letting trip functions express
+	// rules like "trip after N consecutive failures" which the
+	// absolute Failure/Success ratio cannot.
+	ConsecutiveSuccesses int64
+	ConsecutiveFailures  int64
 }
 
-// NewDefaultCircuitBreaker returns circuitbreaker with default settings
-func NewDefaultCircuitBreaker() *CircuitBreaker {
-	return &CircuitBreaker{
-		circuitName:  "Service-B Proxy",
-		currentState: stateClose,
-		currentTime:  time.Now(),
+// Option configures optional settings on a Tracking.
+type Option func(*Tracking)
 
-		tripCircuit: func(counter CircuitCounters) bool {
-			fail := float64(counter.Failure)
-			success := float64(counter.Success)
+// WithInterval sets the duration after which counters are cleared
+// while the circuit is in the closed state, mirroring gobreaker's
+// Interval semantics. An interval <= 0 disables the periodic reset.
+func WithInterval(interval time.Duration) Option {
+	return func(t *Tracking) {
+		t.interval = interval
+	}
+}
+
+// WithMaxRequests sets the number of requests allowed to pass through
+// the circuit while it is half-open. Additional callers are rejected
+// with errTooManyRequests until one of the in-flight probes completes.
+func WithMaxRequests(maxRequests uint32) Option {
+	return func(t *Tracking) {
+		t.maxRequests = maxRequests
+	}
+}
 
-			if (fail+success > 0) && fail/(fail+success) >= 0.50 {
-				return true
-			}
-			return false
-		},
-		untripCircuit: func(counter CircuitCounters) bool {
-			fail := float64(counter.Failure)
-			success := float64(counter.Success)
+// WithOnStateChange registers a callback fired whenever the circuit's
+// state changes, outside of the internal lock.
+func WithOnStateChange(f func(name string, from, to State)) Option {
+	return func(t *Tracking) {
+		t.onStateChange = f
+	}
+}
 
-			if (fail+success > 0) && success/(fail+success) >= 0.50 {
-				return true
-			}
-			return false
-		},
+// WithOnTrip registers a callback fired whenever the circuit trips
+// into the open state.
+func WithOnTrip(f func()) Option {
+	return func(t *Tracking) {
+		t.onTrip = f
+	}
+}
 
-		openTime: 1 * time.Second,
-		counters: &CircuitCounters{},
-		lock:     &sync.Mutex{},
+// WithOnReset registers a callback fired whenever the circuit resets
+// into the closed state.
+func WithOnReset(f func()) Option {
+	return func(t *Tracking) {
+		t.onReset = f
 	}
 }
 
-// NewCircuitBreaker returns circuitbreaker with custom settings
-func NewCircuitBreaker(circuitName string, tripFunc, untripFunc func(CircuitCounters) bool, openT int) *CircuitBreaker {
-	return &CircuitBreaker{
-		circuitName:  circuitName,
-		currentState: stateClose,
-		currentTime:  time.Now(),
+// WithIsSuccessful overrides how a request's error is classified as a
+// circuit failure. The default treats any non-nil error as a failure.
+func WithIsSuccessful(f func(error) bool) Option {
+	return func(t *Tracking) {
+		t.isSuccessful = f
+	}
+}
+
+// WithMetricsSink registers a MetricsSink to receive the circuit's
+// outcome and state-change events. Defaults to a no-op sink.
+func WithMetricsSink(sink MetricsSink) Option {
+	return func(t *Tracking) {
+		t.metrics = sink
+	}
+}
+
+// newTracking builds the Tracking state machine shared by both
+// CircuitBreaker constructors.
+func newTracking(circuitName string, tripFunc, untripFunc func(CircuitCounters) bool, openT time.Duration, opts ...Option) *Tracking {
+	t := &Tracking{
+		circuitName:    circuitName,
+		currentState:   stateClose,
+		currentTime:    time.Now(),
+		stateEnteredAt: time.Now(),
 
 		tripCircuit:   tripFunc,
 		untripCircuit: untripFunc,
 
-		openTime: time.Duration(openT) * time.Second,
-		counters: &CircuitCounters{},
-		lock:     &sync.Mutex{},
+		openTime:     openT,
+		maxRequests:  1,
+		isSuccessful: func(err error) bool { return err == nil },
+		metrics:      noopMetricsSink{},
+		counters:     &CircuitCounters{},
+		lock:         &sync.Mutex{},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// NewDefaultCircuitBreaker returns circuitbreaker with default settings
+func NewDefaultCircuitBreaker[T any](opts ...Option) *CircuitBreaker[T] {
+	defaultTrip := func(counter CircuitCounters) bool {
+		// timeouts count toward the failure side of the ratio, same
+		// as an outright Failure
+		fail := float64(counter.Failure + counter.Timeout)
+		success := float64(counter.Success)
+
+		if (fail+success > 0) && fail/(fail+success) >= 0.50 {
+			return true
+		}
+		return false
+	}
+	defaultUntrip := func(counter CircuitCounters) bool {
+		fail := float64(counter.Failure + counter.Timeout)
+		success := float64(counter.Success)
+
+		if (fail+success > 0) && success/(fail+success) >= 0.50 {
+			return true
+		}
+		return false
+	}
+
+	return &CircuitBreaker[T]{
+		Tracking: newTracking("Service-B Proxy", defaultTrip, defaultUntrip, 1*time.Second, opts...),
+	}
+}
+
+// NewCircuitBreaker returns circuitbreaker with custom settings
+func NewCircuitBreaker[T any](circuitName string, tripFunc, untripFunc func(CircuitCounters) bool, openT int, opts ...Option) *CircuitBreaker[T] {
+	return &CircuitBreaker[T]{
+		Tracking: newTracking(circuitName, tripFunc, untripFunc, time.Duration(openT)*time.Second, opts...),
 	}
 }
 
@@ -137,10 +307,11 @@ func NewCircuitBreaker(circuitName string, tripFunc, untripFunc func(CircuitCoun
 	if the circuit is in close/half-open state request would be passed
 	else if the circuit is in open state request would be failed
 */
-func (cb *CircuitBreaker) Spark(request func() (interface{}, error)) (interface{}, error) {
-	if isOpen(cb) {
-		// create a constant error
-		return nil, errOpen
+func (cb *CircuitBreaker[T]) Spark(request func() (T, error)) (T, error) {
+	generation, err := cb.BeforeRequest()
+	if err != nil {
+		var zero T
+		return zero, err
 	}
 
 	req, err := request()
@@ -148,84 +319,246 @@ func (cb *CircuitBreaker) Spark(request func() (interface{}, error)) (interface{
 	defer func() {
 		e := recover()
 		if e != nil {
-			onFail(cb)
+			cb.AfterRequest(generation, false)
 			panic(e)
 		}
 	}()
 
+	cb.AfterRequest(generation, cb.isSuccessful(err))
 	if err != nil {
-		onFail(cb)
 		return req, err
 	}
-	onSuccess(cb)
 	return req, nil
 }
 
-func isOpen(cb *CircuitBreaker) bool {
-	// isOpen veirifies if circuit is open or not
-	cb.lock.Lock()
-	defer cb.lock.Unlock()
+/*
+	SparkContext behaves like Spark, but runs request in a goroutine
+	racing against ctx so that a caller-supplied deadline is accounted
+	for as a Timeout instead of Spark simply blocking until request
+	returns. If ctx is already done, the call is rejected with errOpen
+	without ever reaching the circuit's trip/untrip logic.
+*/
+func (cb *CircuitBreaker[T]) SparkContext(ctx context.Context, request func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if ctx.Err() != nil {
+		return zero, errOpen
+	}
+
+	generation, err := cb.BeforeRequest()
+	if err != nil {
+		return zero, err
+	}
+
+	type result struct {
+		req T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		req, err := request(ctx)
+		done <- result{req, err}
+	}()
 
-	updateState(cb)
-	state := cb.currentState
-	if state == stateOpen {
-		return true
+	select {
+	case <-ctx.Done():
+		cb.AfterRequestTimeout(generation)
+		return zero, ctx.Err()
+	case r := <-done:
+		cb.AfterRequest(generation, cb.isSuccessful(r.err))
+		return r.req, r.err
 	}
-	return false
 }
 
-func onFail(cb *CircuitBreaker) {
-	// increment the failure counter and update state
-	cb.lock.Lock()
-	defer cb.lock.Unlock()
+// maxHalfOpenRequests returns t.maxRequests, treating an unset (zero)
+// value as 1 probe at a time.
+func (t *Tracking) maxHalfOpenRequests() uint32 {
+	if t.maxRequests == 0 {
+		return 1
+	}
+	return t.maxRequests
+}
+
+// BeforeRequest checks whether a request may pass through the circuit
+// and, if the circuit is half-open, counts it as an in-flight probe.
+// It returns errOpen if the
+// circuit is open, and errTooManyRequests if the circuit is half-open
+// and already has MaxRequests probes in-flight. The returned
+// generation must be passed back to AfterRequest once the call
+// completes.
+func (t *Tracking) BeforeRequest() (generation uint64, err error) {
+	t.lock.Lock()
+
+	tr, changed := updateState(t)
+	generation = t.generation
+
+	rejected := false
+	switch t.currentState {
+	case stateOpen:
+		err = errOpen
+		t.counters.Rejection++
+		rejected = true
+	case stateHalfOpen:
+		if atomic.AddUint32(&t.halfOpenRequests, 1) > t.maxHalfOpenRequests() {
+			atomic.AddUint32(&t.halfOpenRequests, ^uint32(0))
+			err = errTooManyRequests
+			t.counters.Rejection++
+			rejected = true
+		}
+	}
+
+	t.lock.Unlock()
 
-	cb.counters.Failure++
-	updateState(cb)
+	if rejected {
+		t.metrics.RecordRejection(t.circuitName)
+	}
+	if changed {
+		t.notify(tr)
+	}
+	return generation, err
 }
 
-func onSuccess(cb *CircuitBreaker) {
-	// increment the success counter and update state
-	cb.lock.Lock()
-	defer cb.lock.Unlock()
+// AfterRequest records the outcome of a request previously admitted by
+// BeforeRequest. If the circuit has since moved on to a new generation
+// (i.e. a state transition happened while the request was in flight),
+// the result is stale and is dropped instead of corrupting the new
+// generation's counters.
+func (t *Tracking) AfterRequest(generation uint64, success bool) {
+	t.lock.Lock()
+
+	if generation != t.generation {
+		t.lock.Unlock()
+		return
+	}
 
-	cb.counters.Success++
-	updateState(cb)
+	if t.currentState == stateHalfOpen {
+		atomic.AddUint32(&t.halfOpenRequests, ^uint32(0))
+	}
+
+	if success {
+		t.counters.Success++
+		t.counters.ConsecutiveSuccesses++
+		t.counters.ConsecutiveFailures = 0
+	} else {
+		t.counters.Failure++
+		t.counters.ConsecutiveFailures++
+		t.counters.ConsecutiveSuccesses = 0
+	}
+	tr, changed := updateState(t)
+
+	t.lock.Unlock()
+
+	if success {
+		t.metrics.RecordSuccess(t.circuitName)
+	} else {
+		t.metrics.RecordFailure(t.circuitName)
+	}
+	if changed {
+		t.notify(tr)
+	}
 }
 
-func updateState(cb *CircuitBreaker) {
+// AfterRequestTimeout records a request previously admitted by
+// BeforeRequest as having timed out, incrementing the Timeout counter
+// instead of Failure/Success. Used by SparkContext when ctx.Done()
+// fires before the request returns. A timed-out request is treated as
+// stale the same way a regular result is: if the generation has moved
+// on, it's dropped.
+func (t *Tracking) AfterRequestTimeout(generation uint64) {
+	t.lock.Lock()
+
+	if generation != t.generation {
+		t.lock.Unlock()
+		return
+	}
+
+	if t.currentState == stateHalfOpen {
+		atomic.AddUint32(&t.halfOpenRequests, ^uint32(0))
+	}
+
+	t.counters.Timeout++
+	t.counters.ConsecutiveFailures++
+	t.counters.ConsecutiveSuccesses = 0
+
+	tr, changed := updateState(t)
+
+	t.lock.Unlock()
+
+	t.metrics.RecordTimeout(t.circuitName)
+	if changed {
+		t.notify(tr)
+	}
+}
+
+// updateState evaluates trip/untrip/expiry rules for the current state
+// and, if a transition happened, reports it so the caller can fire the
+// state-change callbacks once the lock is released.
+func updateState(t *Tracking) (tr transition, changed bool) {
 	// whenever state changes we reset the counters
-	switch cb.currentState {
+	switch t.currentState {
 	case stateClose:
-		if cb.tripCircuit(*cb.counters) {
-			cb.currentState = stateOpen
-			cb.currentTime = time.Now()
-			cb.ResetCounters()
+		if t.interval > 0 && t.currentTime.Add(t.interval).Before(time.Now()) {
+			t.currentTime = time.Now()
+			t.ResetCounters()
+		}
+		if t.tripCircuit(*t.counters) {
+			return t.transitionTo(stateOpen), true
 		}
 	case stateHalfOpen:
-		if cb.counters.Failure > 0 {
-			cb.currentState = stateOpen
-			cb.currentTime = time.Now()
-			cb.ResetCounters()
+		if t.counters.Failure > 0 || t.counters.Timeout > 0 {
+			return t.transitionTo(stateOpen), true
+		} else if t.counters.Success >= int64(t.maxHalfOpenRequests()) && t.untripCircuit(*t.counters) {
+			return t.transitionTo(stateClose), true
 		}
-		if cb.untripCircuit(*cb.counters) {
-			cb.currentState = stateClose
-			cb.currentTime = time.Now()
-			cb.ResetCounters()
+	case stateOpen:
+		if t.currentTime.Add(t.openTime).Before(time.Now()) {
+			return t.transitionTo(stateHalfOpen), true
 		}
+	}
+	return transition{}, false
+}
+
+// transitionTo moves the circuit to a new state, resetting its
+// counters and bumping the generation so in-flight AfterRequest calls
+// from the previous state are recognised as stale.
+func (t *Tracking) transitionTo(state State) transition {
+	now := time.Now()
+	tr := transition{from: t.currentState, to: state, duration: now.Sub(t.stateEnteredAt)}
+	t.currentState = state
+	t.currentTime = now
+	t.stateEnteredAt = now
+	t.generation++
+	t.halfOpenRequests = 0
+	t.ResetCounters()
+	return tr
+}
+
+// notify fires OnStateChange, RecordStateChange and, where applicable,
+// OnTrip/OnReset for the given transition. Must be called without
+// holding t.lock.
+func (t *Tracking) notify(tr transition) {
+	if t.onStateChange != nil {
+		t.onStateChange(t.circuitName, tr.from, tr.to)
+	}
+	switch tr.to {
 	case stateOpen:
-		if cb.currentTime.Add(cb.openTime).Before(time.Now()) {
-			cb.currentState = stateHalfOpen
-			cb.currentTime = time.Now()
-			cb.ResetCounters()
+		if t.onTrip != nil {
+			t.onTrip()
+		}
+	case stateClose:
+		if t.onReset != nil {
+			t.onReset()
 		}
 	}
+	t.metrics.RecordStateChange(t.circuitName, tr.from, tr.to, tr.duration)
 }
 
 // ResetCounters will reset circuit counters
 // It is invoked when state changes
-func (cb *CircuitBreaker) ResetCounters() {
-	cb.counters.Failure = 0
-	cb.counters.Success = 0
-	cb.counters.Timeout = 0
-	cb.counters.Rejection = 0
+func (t *Tracking) ResetCounters() {
+	t.counters.Failure = 0
+	t.counters.Success = 0
+	t.counters.Timeout = 0
+	t.counters.Rejection = 0
+	t.counters.ConsecutiveSuccesses = 0
+	t.counters.ConsecutiveFailures = 0
 }
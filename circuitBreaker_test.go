@@ -1,6 +1,9 @@
 package circuitbreaker
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,7 +14,7 @@ func TestNewDefaultCircuitBreaker(t *testing.T) {
 	/*
 		Testing Circuit Breaker with default settings
 	*/
-	cb := NewDefaultCircuitBreaker()
+	cb := NewDefaultCircuitBreaker[interface{}]()
 
 	assert.Equal(t, cb.openTime, 1*time.Second, "correct open timeout")
 	assert.Equal(t, cb.circuitName, "Service-B Proxy", "correct service name")
@@ -22,7 +25,7 @@ func TestNewCircuitBreaker(t *testing.T) {
 	/*
 		Testing Circuit Breaker with custom settings
 	*/
-	cb := NewCircuitBreaker("my-circuit", testTripFunc, testUntripFunc, 2)
+	cb := NewCircuitBreaker[interface{}]("my-circuit", testTripFunc, testUntripFunc, 2)
 
 	assert.Equal(t, cb.openTime, 2*time.Second, "correct open timeout")
 	assert.Equal(t, cb.circuitName, "my-circuit", "correct service name")
@@ -39,7 +42,7 @@ func TestSpark_DefaultSettings(t *testing.T) {
 	// Request should be successful and state should remain close.
 
 	// setup
-	cb := NewDefaultCircuitBreaker()
+	cb := NewDefaultCircuitBreaker[interface{}]()
 
 	// test with success call
 	_, err := cb.Spark(doSuccessCall)
@@ -73,7 +76,7 @@ func TestSpark_DefaultSettings(t *testing.T) {
 	// validate request should fail as circuit in open state
 	assert.NotNil(t, err, "no error in successful calls")
 	assert.Equal(t, err, errOpen, "Circuit in open state should fail request")
-	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 0, Success: 0, Timeout: 0, Rejection: 0}, "Counters should be reset after state change")
+	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 0, Success: 0, Timeout: 0, Rejection: 1}, "Rejection counter should be incremented for a short-circuited call")
 	assert.Equal(t, cb.currentState, stateOpen, "Circuit should be in open state for openTimeout Duration")
 
 	// TEST-4
@@ -128,7 +131,7 @@ func TestSpark_CustomSettings(t *testing.T) {
 	// setup
 	openTime := 2
 	// Circuit Breaker with user-defined custom settings
-	cb := NewCircuitBreaker("Service-A", testTripFunc, testUntripFunc, openTime)
+	cb := NewCircuitBreaker[interface{}]("Service-A", testTripFunc, testUntripFunc, openTime)
 
 	// TEST-1
 	// Circuit in initial close state and would try a request
@@ -154,7 +157,7 @@ func TestSpark_CustomSettings(t *testing.T) {
 	// validate circuit state based on custom defined trip function
 	assert.NotNil(t, err, "Received error from fail call")
 	assert.Equal(t, err, errFailed, "Request to service failed")
-	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 1, Success: 1, Timeout: 0, Rejection: 0}, "State should be closed after successful requests")
+	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 1, Success: 1, Timeout: 0, Rejection: 0, ConsecutiveFailures: 1}, "State should be closed after successful requests")
 	assert.Equal(t, cb.currentState, stateClose, "Circuit should not trip if error rate did not exceeds 50%")
 
 	// circuit will trip based on custom defined trip function if one more fail request is passed
@@ -172,12 +175,12 @@ func TestSpark_CustomSettings(t *testing.T) {
 
 	// validate request should fail as circuit in open state
 	assert.NotNil(t, err, "no error in successful calls")
-	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 0, Success: 0, Timeout: 0, Rejection: 0}, "State should be closed after successful requests")
+	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 0, Success: 0, Timeout: 0, Rejection: 1}, "Rejection counter should be incremented for a short-circuited call")
 	assert.Equal(t, cb.currentState, stateOpen, "Circuit should trip if error rate exceeds 50%")
 
 	// sleeping for less than open time state still should be open
 	time.Sleep(time.Duration(openTime-1) * time.Second)
-	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 0, Success: 0, Timeout: 0, Rejection: 0}, "State should be closed after successful requests")
+	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 0, Success: 0, Timeout: 0, Rejection: 1}, "State should be closed after successful requests")
 	assert.Equal(t, cb.currentState, stateOpen, "Circuit should trip if error rate exceeds 50%")
 
 	// TEST-4
@@ -280,3 +283,488 @@ func testUntripFunc(counter CircuitCounters) bool {
 	}
 	return false
 }
+
+func testConsecutiveFailureTripFunc(counter CircuitCounters) bool {
+	// trip circuit after 3 consecutive failures, regardless of
+	// the overall success/failure ratio
+	return counter.ConsecutiveFailures >= 3
+}
+
+func TestSpark_IntervalResetsCountersWhileClosed(t *testing.T) {
+	/*
+		Testing that counters are cleared mid closed-state once
+		Interval elapses, even though no state transition occurs.
+	*/
+
+	// setup a circuit that never trips so the circuit stays closed
+	neverTrip := func(counter CircuitCounters) bool { return false }
+	cb := NewCircuitBreaker[interface{}]("interval-circuit", neverTrip, testUntripFunc, 1, WithInterval(1*time.Second))
+
+	_, err := cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+	assert.Equal(t, cb.counters.Failure, int64(1), "Failure counter incremented")
+	assert.Equal(t, cb.currentState, stateClose, "Circuit should remain closed as trip func never trips")
+
+	// wait for the interval to elapse
+	time.Sleep(1 * time.Second)
+
+	// next call should observe cleared counters before recording itself
+	_, err = cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+	assert.Equal(t, cb.counters.Failure, int64(1), "Counters should have been cleared by Interval before this call")
+	assert.Equal(t, cb.currentState, stateClose, "Circuit should remain closed as trip func never trips")
+}
+
+func TestSpark_IntervalDisabledByDefault(t *testing.T) {
+	/*
+		Testing that counters are not cleared mid closed-state when
+		Interval is left unset (current/default behaviour).
+	*/
+
+	neverTrip := func(counter CircuitCounters) bool { return false }
+	cb := NewCircuitBreaker[interface{}]("no-interval-circuit", neverTrip, testUntripFunc, 1)
+
+	cb.Spark(doFailCall)
+	time.Sleep(1100 * time.Millisecond)
+	cb.Spark(doFailCall)
+
+	assert.Equal(t, cb.counters.Failure, int64(2), "Counters should accumulate without Interval set")
+}
+
+func TestSpark_ConsecutiveFailureTrip(t *testing.T) {
+	/*
+		Testing a trip function based on ConsecutiveFailures, which
+		the absolute Failure/Success ratio counters cannot express.
+	*/
+
+	cb := NewCircuitBreaker[interface{}]("consecutive-circuit", testConsecutiveFailureTripFunc, testUntripFunc, 1)
+
+	// two failures interleaved with a success should not trip the
+	// circuit since the failure streak is broken
+	cb.Spark(doFailCall)
+	cb.Spark(doFailCall)
+	_, err := cb.Spark(doSuccessCall)
+	assert.Nil(t, err, "no error in success call")
+	assert.Equal(t, cb.counters.ConsecutiveFailures, int64(0), "streak reset by success")
+	assert.Equal(t, cb.currentState, stateClose, "Circuit should not trip as failure streak was broken")
+
+	// three consecutive failures should trip the circuit
+	cb.Spark(doFailCall)
+	cb.Spark(doFailCall)
+	_, err = cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+	assert.Equal(t, cb.currentState, stateOpen, "Circuit should trip after 3 consecutive failures")
+}
+
+func TestSpark_HalfOpenMaxRequestsLimitsConcurrentProbes(t *testing.T) {
+	/*
+		Testing that only MaxRequests callers are admitted concurrently
+		while the circuit is half-open; extras are rejected immediately
+		with errTooManyRequests.
+	*/
+
+	// setup a circuit that trips on any failure and open for 1 second
+	alwaysTrip := func(counter CircuitCounters) bool { return counter.Failure > 0 }
+	cb := NewCircuitBreaker[interface{}]("half-open-circuit", alwaysTrip, testUntripFunc, 1, WithMaxRequests(2))
+
+	// trip the circuit
+	_, err := cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+	assert.Equal(t, cb.currentState, stateOpen, "Circuit should trip on failure")
+
+	// wait for the circuit to become half-open
+	time.Sleep(1100 * time.Millisecond)
+
+	release := make(chan struct{})
+	blockingCall := func() (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, results[i] = cb.Spark(blockingCall)
+		}(i)
+	}
+
+	// give the goroutines time to reach the half-open gate
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	rejected := 0
+	for _, err := range results {
+		if err == errTooManyRequests {
+			rejected++
+		}
+	}
+	assert.Equal(t, rejected, 1, "only MaxRequests probes should be admitted concurrently")
+}
+
+func TestTracking_BeforeAfterRequest(t *testing.T) {
+	/*
+		Testing the Tracking state machine directly, without going
+		through Spark's func() (T, error) closure model.
+	*/
+
+	tracking := newTracking("tracking-circuit", testTripFunc, testUntripFunc, 1*time.Second)
+
+	generation, err := tracking.BeforeRequest()
+	assert.Nil(t, err, "circuit should admit the request while closed")
+
+	tracking.AfterRequest(generation, true)
+	assert.Equal(t, tracking.counters.Success, int64(1), "success recorded against the circuit")
+	assert.Equal(t, tracking.currentState, stateClose, "circuit should remain closed after one success")
+
+	generation, err = tracking.BeforeRequest()
+	assert.Nil(t, err, "circuit should still admit the request while closed")
+	tracking.AfterRequest(generation, false)
+	assert.Equal(t, tracking.currentState, stateClose, "failure ratio of 50% should not yet trip testTripFunc")
+
+	generation, err = tracking.BeforeRequest()
+	assert.Nil(t, err, "circuit should still admit the request while closed")
+	tracking.AfterRequest(generation, false)
+	assert.Equal(t, tracking.currentState, stateOpen, "circuit should trip once the failure ratio exceeds 50%")
+}
+
+func TestTracking_StaleAfterRequestIsIgnored(t *testing.T) {
+	/*
+		Testing that an AfterRequest call carrying a generation from a
+		previous state is dropped rather than corrupting the new
+		state's counters.
+	*/
+
+	alwaysTrip := func(counter CircuitCounters) bool { return counter.Failure > 0 }
+	tracking := newTracking("stale-circuit", alwaysTrip, testUntripFunc, 1*time.Second)
+
+	generation, err := tracking.BeforeRequest()
+	assert.Nil(t, err, "circuit should admit the request while closed")
+
+	// a second request trips the circuit, advancing the generation
+	// before the first request's result arrives
+	g2, err := tracking.BeforeRequest()
+	assert.Nil(t, err, "circuit should admit the second request while still closed")
+	tracking.AfterRequest(g2, false)
+	assert.Equal(t, tracking.currentState, stateOpen, "circuit should trip on failure")
+
+	// the stale result from the first (now superseded) generation
+	// must not be applied to the new generation's counters
+	tracking.AfterRequest(generation, true)
+	assert.Equal(t, tracking.counters.Success, int64(0), "stale success must not be recorded")
+	assert.Equal(t, tracking.currentState, stateOpen, "stale result must not reopen the circuit")
+}
+
+func TestSpark_StateChangeCallbacks(t *testing.T) {
+	/*
+		Testing that OnStateChange fires for every transition, in order,
+		and that OnTrip/OnReset fire specifically for open/close
+		transitions, all without deadlocking the breaker.
+	*/
+
+	var mu sync.Mutex
+	var changes []string
+	tripped := 0
+	reset := 0
+
+	var cb *CircuitBreaker[interface{}]
+	alwaysTrip := func(counter CircuitCounters) bool { return counter.Failure > 0 }
+	cb = NewCircuitBreaker[interface{}]("callback-circuit", alwaysTrip, testUntripFunc, 1,
+		WithOnStateChange(func(name string, from, to State) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, name+":"+from.String()+"->"+to.String())
+
+			// taking the breaker's lock from the callback must not deadlock
+			cb.lock.Lock()
+			_ = cb.currentState
+			cb.lock.Unlock()
+		}),
+		WithOnTrip(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			tripped++
+		}),
+		WithOnReset(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			reset++
+		}),
+	)
+
+	// close -> open
+	_, err := cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+
+	// wait for open -> half-open
+	time.Sleep(1100 * time.Millisecond)
+
+	// half-open -> close
+	_, err = cb.Spark(doSuccessCall)
+	assert.Nil(t, err, "no error in success call")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, changes, []string{
+		"callback-circuit:close->open",
+		"callback-circuit:open->half-open",
+		"callback-circuit:half-open->close",
+	}, "OnStateChange should fire once per transition, in order")
+	assert.Equal(t, tripped, 1, "OnTrip should fire exactly once, for the open transition")
+	assert.Equal(t, reset, 1, "OnReset should fire exactly once, for the close transition")
+}
+
+func TestSpark_IsSuccessfulClassifier(t *testing.T) {
+	/*
+		Testing that a custom IsSuccessful classifier can prevent an
+		error from counting as a circuit failure, while the error is
+		still returned to the caller.
+	*/
+
+	classifiedSuccess := errors.New("not a real failure")
+	alwaysTrip := func(counter CircuitCounters) bool { return counter.Failure > 0 }
+	cb := NewCircuitBreaker[interface{}]("classifier-circuit", alwaysTrip, testUntripFunc, 1,
+		WithIsSuccessful(func(err error) bool {
+			return err == nil || err == classifiedSuccess
+		}),
+	)
+
+	_, err := cb.Spark(func() (interface{}, error) { return nil, classifiedSuccess })
+	assert.Equal(t, err, classifiedSuccess, "classified error should still be returned to the caller")
+	assert.Equal(t, cb.currentState, stateClose, "circuit should not trip on a classified-success error")
+	assert.Equal(t, cb.counters.Success, int64(1), "classified-success error should be counted as a success")
+
+	_, err = cb.Spark(doFailCall)
+	assert.Equal(t, err, errFailed, "Received error from fail call")
+	assert.Equal(t, cb.currentState, stateOpen, "circuit should still trip on an unclassified failure")
+}
+
+func TestSparkContext_RejectsAlreadyExpiredContext(t *testing.T) {
+	/*
+		Testing that SparkContext returns errOpen immediately for an
+		already-expired context, without ever touching the circuit's
+		counters or trip logic.
+	*/
+
+	cb := NewCircuitBreaker[interface{}]("context-circuit", testTripFunc, testUntripFunc, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := cb.SparkContext(ctx, doSuccessCallContext)
+	assert.Equal(t, err, errOpen, "an already-expired context should be rejected like an open circuit")
+	assert.Equal(t, cb.counters, &CircuitCounters{}, "counters should be untouched by the pre-flight ctx check")
+}
+
+func TestSparkContext_TimeoutIncrementsCounterAndCanTripCircuit(t *testing.T) {
+	/*
+		Testing that a context deadline firing before the request
+		returns is recorded as a Timeout rather than blocking forever,
+		and that the default trip function counts Timeout the same way
+		it counts Failure.
+	*/
+
+	cb := NewDefaultCircuitBreaker[interface{}]()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := cb.SparkContext(ctx, doSlowCallContext)
+	assert.Equal(t, err, context.DeadlineExceeded, "SparkContext should return the context's error on timeout")
+	assert.Equal(t, cb.counters, &CircuitCounters{}, "circuit tripped, so its counters were reset")
+	assert.Equal(t, cb.currentState, stateOpen, "a single timeout should be enough to trip the default trip function")
+}
+
+func TestSparkContext_TimeoutWhileHalfOpenReopensCircuit(t *testing.T) {
+	/*
+		Testing that a probe timing out while the circuit is half-open
+		reopens it, the same way a Failure does. Before this fix,
+		updateState's stateHalfOpen branch only checked counters.Failure,
+		so a timed-out probe just freed the half-open slot and let more
+		probes through a backend that was still failing.
+	*/
+
+	cb := NewCircuitBreaker[interface{}]("half-open-timeout-circuit", testTripFunc, testUntripFunc, 0, WithMaxRequests(1))
+
+	_, err := cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+	assert.Equal(t, cb.currentState, stateOpen, "Circuit should trip on the first failure")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// OpenTime is 0, so this call's BeforeRequest lazily transitions the
+	// circuit from open to half-open and admits it as the lone probe.
+	_, err = cb.SparkContext(ctx, doSlowCallContext)
+	assert.Equal(t, err, context.DeadlineExceeded, "SparkContext should return the context's error on timeout")
+	assert.Equal(t, cb.currentState, stateOpen, "a timeout while half-open should reopen the circuit, not leave it half-open")
+}
+
+func TestSparkContext_SuccessfulCallIsRecordedNormally(t *testing.T) {
+	/*
+		Testing that a request which completes before ctx.Done() fires
+		is recorded exactly like a normal Spark success.
+	*/
+
+	cb := NewCircuitBreaker[interface{}]("context-success-circuit", testTripFunc, testUntripFunc, 1)
+
+	req, err := cb.SparkContext(context.Background(), doSuccessCallContext)
+	assert.Nil(t, err, "no error expected from a successful call")
+	assert.Nil(t, req, "doSuccessCallContext returns a nil value")
+	assert.Equal(t, cb.counters, &CircuitCounters{Failure: 0, Success: 1, Timeout: 0, Rejection: 0, ConsecutiveSuccesses: 1}, "Success counter should be incremented")
+}
+
+func doSuccessCallContext(ctx context.Context) (interface{}, error) {
+	return nil, nil
+}
+
+func doSlowCallContext(ctx context.Context) (interface{}, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type recordingMetricsSink struct {
+	mu           sync.Mutex
+	successes    int
+	failures     int
+	rejections   int
+	timeouts     int
+	changes      []string
+	lastDuration time.Duration
+}
+
+func (s *recordingMetricsSink) RecordSuccess(circuitName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.successes++
+}
+
+func (s *recordingMetricsSink) RecordFailure(circuitName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+}
+
+func (s *recordingMetricsSink) RecordRejection(circuitName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejections++
+}
+
+func (s *recordingMetricsSink) RecordTimeout(circuitName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeouts++
+}
+
+func (s *recordingMetricsSink) RecordStateChange(circuitName string, from, to State, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, from.String()+"->"+to.String())
+	if duration <= 0 {
+		panic("RecordStateChange should observe a positive duration spent in the from state")
+	}
+	s.lastDuration = duration
+}
+
+func TestSpark_MetricsSinkRecordsOutcomesAndStateChanges(t *testing.T) {
+	/*
+		Testing that a registered MetricsSink observes recorded outcomes
+		(here a failure and a rejection) and state transitions, without
+		any OnStateChange/OnTrip/OnReset callback registered.
+	*/
+
+	sink := &recordingMetricsSink{}
+	alwaysTrip := func(counter CircuitCounters) bool { return counter.Failure > 0 }
+	cb := NewCircuitBreaker[interface{}]("metrics-circuit", alwaysTrip, testUntripFunc, 1, WithMetricsSink(sink))
+
+	_, err := cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+
+	_, err = cb.Spark(doSuccessCall)
+	assert.Equal(t, err, errOpen, "Circuit in open state should reject the call")
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Equal(t, sink.failures, 1, "one failure should have been recorded")
+	assert.Equal(t, sink.rejections, 1, "one rejection should have been recorded")
+	assert.Equal(t, sink.changes, []string{"close->open"}, "one state change should have been recorded")
+}
+
+func TestSpark_MetricsSinkDurationSurvivesIntervalReset(t *testing.T) {
+	/*
+		Testing that RecordStateChange's duration reflects the actual
+		time spent in the "from" state, not just the time since the
+		last periodic Interval reset. A breaker with both WithInterval
+		and WithMetricsSink previously reported a close->open duration
+		of only a few microseconds (time since the last interval tick)
+		instead of the real time spent closed.
+	*/
+
+	sink := &recordingMetricsSink{}
+	alwaysTrip := func(counter CircuitCounters) bool { return counter.Failure > 0 }
+	cb := NewCircuitBreaker[interface{}]("interval-metrics-circuit", alwaysTrip, testUntripFunc, 1,
+		WithInterval(50*time.Millisecond), WithMetricsSink(sink))
+
+	started := time.Now()
+	time.Sleep(120 * time.Millisecond) // let more than one Interval elapse while closed; the reset is applied on the next call
+
+	_, err := cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+	elapsed := time.Since(started)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Equal(t, sink.changes, []string{"close->open"}, "one state change should have been recorded")
+	assert.True(t, sink.lastDuration >= 100*time.Millisecond,
+		"duration should reflect time actually spent closed, not time since the last interval reset")
+	assert.True(t, sink.lastDuration <= elapsed+10*time.Millisecond,
+		"duration should not exceed the actual wall-clock time spent closed")
+}
+
+func TestBeforeRequest_HalfOpenOverflowRecordsRejection(t *testing.T) {
+	/*
+		Testing that errTooManyRequests rejections (half-open probes
+		beyond MaxRequests) are observable through the same counters
+		and MetricsSink as stateOpen rejections.
+	*/
+
+	sink := &recordingMetricsSink{}
+	alwaysTrip := func(counter CircuitCounters) bool { return counter.Failure > 0 }
+	cb := NewCircuitBreaker[interface{}]("half-open-overflow-circuit", alwaysTrip, testUntripFunc, 0,
+		WithMaxRequests(1), WithMetricsSink(sink))
+
+	_, err := cb.Spark(doFailCall)
+	assert.NotNil(t, err, "Received error from fail call")
+
+	// OpenTime is 0, so the next BeforeRequest call lazily transitions
+	// the circuit from open straight to half-open and admits as the
+	// first probe.
+	_, err = cb.BeforeRequest()
+	assert.Nil(t, err, "first half-open probe should be admitted")
+	assert.Equal(t, cb.currentState, stateHalfOpen, "Circuit should be half-open once OpenTime elapses")
+
+	_, err = cb.BeforeRequest()
+	assert.Equal(t, err, errTooManyRequests, "second concurrent half-open probe should overflow MaxRequests")
+
+	assert.Equal(t, cb.counters.Rejection, int64(1), "overflow rejection should be counted")
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	assert.Equal(t, sink.rejections, 1, "overflow rejection should be observable through the MetricsSink")
+}
+
+func TestNewDefaultCircuitBreaker_DefaultsToNoopMetricsSink(t *testing.T) {
+	/*
+		Testing that a CircuitBreaker built without WithMetricsSink
+		still works; it silently discards every event.
+	*/
+
+	cb := NewDefaultCircuitBreaker[interface{}]()
+	_, err := cb.Spark(doSuccessCall)
+	assert.Nil(t, err, "no error in success call")
+	assert.IsType(t, noopMetricsSink{}, cb.metrics, "default metrics sink should be the no-op sink")
+}
@@ -10,8 +10,8 @@ import (
 
 // Get has a Circuit Breaker middleware
 func Get(url string) ([]byte, error) {
-	cb := circuitbreaker.NewCircuitBreaker()
-	body, err := cb.Spark(func() (interface{}, error) {
+	cb := circuitbreaker.NewDefaultCircuitBreaker[[]byte]()
+	body, err := cb.Spark(func() ([]byte, error) {
 		resp, err := http.Get(url)
 		if err != nil {
 			return nil, err
@@ -29,7 +29,7 @@ func Get(url string) ([]byte, error) {
 		return nil, err
 	}
 
-	return body.([]byte), nil
+	return body, nil
 }
 func main() {
 
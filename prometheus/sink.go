@@ -0,0 +1,79 @@
+/*
+	Package prometheus adapts circuitbreaker.MetricsSink onto Prometheus
+	counters and a state gauge, so the core circuitbreaker package stays
+	free of the client_golang dependency for callers who don't want it.
+*/
+package prometheus
+
+import (
+	"time"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+
+	circuitbreaker "github.com/haseth/spark"
+)
+
+// Sink is a circuitbreaker.MetricsSink backed by Prometheus counters
+// (success/failure/rejection/timeout) and a state gauge, all labeled
+// by circuitName.
+type Sink struct {
+	success   *promclient.CounterVec
+	failure   *promclient.CounterVec
+	rejection *promclient.CounterVec
+	timeout   *promclient.CounterVec
+	state     *promclient.GaugeVec
+}
+
+// NewSink builds a Sink and registers its metrics with registerer.
+func NewSink(registerer promclient.Registerer) *Sink {
+	s := &Sink{
+		success: promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "spark_circuit_success_total",
+			Help: "Total number of requests the circuit breaker recorded as successful.",
+		}, []string{"circuit"}),
+		failure: promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "spark_circuit_failure_total",
+			Help: "Total number of requests the circuit breaker recorded as failed.",
+		}, []string{"circuit"}),
+		rejection: promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "spark_circuit_rejection_total",
+			Help: "Total number of requests rejected by an open circuit or by the half-open MaxRequests limit.",
+		}, []string{"circuit"}),
+		timeout: promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "spark_circuit_timeout_total",
+			Help: "Total number of requests the circuit breaker recorded as timed out.",
+		}, []string{"circuit"}),
+		state: promclient.NewGaugeVec(promclient.GaugeOpts{
+			Name: "spark_circuit_state",
+			Help: "Current state of the circuit: 0=close, 1=open, 2=half-open.",
+		}, []string{"circuit"}),
+	}
+
+	registerer.MustRegister(s.success, s.failure, s.rejection, s.timeout, s.state)
+	return s
+}
+
+func (s *Sink) RecordSuccess(circuitName string) {
+	s.success.WithLabelValues(circuitName).Inc()
+}
+
+func (s *Sink) RecordFailure(circuitName string) {
+	s.failure.WithLabelValues(circuitName).Inc()
+}
+
+func (s *Sink) RecordRejection(circuitName string) {
+	s.rejection.WithLabelValues(circuitName).Inc()
+}
+
+func (s *Sink) RecordTimeout(circuitName string) {
+	s.timeout.WithLabelValues(circuitName).Inc()
+}
+
+// RecordStateChange sets the state gauge for circuitName. duration (how
+// long the circuit spent in the previous state) isn't surfaced by this
+// sink today, but is accepted to satisfy circuitbreaker.MetricsSink.
+func (s *Sink) RecordStateChange(circuitName string, from, to circuitbreaker.State, duration time.Duration) {
+	s.state.WithLabelValues(circuitName).Set(float64(to))
+}
+
+var _ circuitbreaker.MetricsSink = (*Sink)(nil)
@@ -0,0 +1,72 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	circuitbreaker "github.com/haseth/spark"
+)
+
+func TestSink_RecordSuccessIncrementsLabeledCounter(t *testing.T) {
+	registry := promclient.NewPedanticRegistry()
+	sink := NewSink(registry)
+
+	sink.RecordSuccess("orders-service")
+	sink.RecordSuccess("orders-service")
+	sink.RecordFailure("orders-service")
+	sink.RecordRejection("orders-service")
+	sink.RecordTimeout("orders-service")
+
+	expected := `
+		# HELP spark_circuit_success_total Total number of requests the circuit breaker recorded as successful.
+		# TYPE spark_circuit_success_total counter
+		spark_circuit_success_total{circuit="orders-service"} 2
+	`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "spark_circuit_success_total")
+	assert.Nil(t, err, "success counter should be labeled and incremented per circuitName")
+
+	assert.Equal(t, testutil.ToFloat64(sink.failure.WithLabelValues("orders-service")), float64(1), "failure counter incremented")
+	assert.Equal(t, testutil.ToFloat64(sink.rejection.WithLabelValues("orders-service")), float64(1), "rejection counter incremented")
+	assert.Equal(t, testutil.ToFloat64(sink.timeout.WithLabelValues("orders-service")), float64(1), "timeout counter incremented")
+}
+
+func TestSink_RecordStateChangeSetsStateGauge(t *testing.T) {
+	registry := promclient.NewPedanticRegistry()
+	sink := NewSink(registry)
+
+	sink.RecordStateChange("orders-service", circuitbreaker.State(0), circuitbreaker.State(1), 0)
+
+	expected := `
+		# HELP spark_circuit_state Current state of the circuit: 0=close, 1=open, 2=half-open.
+		# TYPE spark_circuit_state gauge
+		spark_circuit_state{circuit="orders-service"} 1
+	`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "spark_circuit_state")
+	assert.Nil(t, err, "state gauge should reflect the latest transition's to-state")
+
+	sink.RecordStateChange("orders-service", circuitbreaker.State(1), circuitbreaker.State(2), 0)
+	assert.Equal(t, testutil.ToFloat64(sink.state.WithLabelValues("orders-service")), float64(2), "state gauge should update on a later transition")
+}
+
+func TestSink_RegistersAllMetricsOnConstruction(t *testing.T) {
+	registry := promclient.NewPedanticRegistry()
+	sink := NewSink(registry)
+
+	metricFamilies, err := registry.Gather()
+	assert.Nil(t, err, "registered collectors should gather without error")
+	assert.Equal(t, len(metricFamilies), 0, "no series are emitted until an event is recorded")
+
+	sink.RecordSuccess("orders-service")
+	sink.RecordFailure("orders-service")
+	sink.RecordRejection("orders-service")
+	sink.RecordTimeout("orders-service")
+	sink.RecordStateChange("orders-service", circuitbreaker.State(0), circuitbreaker.State(1), 0)
+
+	metricFamilies, err = registry.Gather()
+	assert.Nil(t, err, "registered collectors should gather without error")
+	assert.Equal(t, len(metricFamilies), 5, "all five metrics should be registered with the registerer")
+}